@@ -0,0 +1,100 @@
+package mtOpenGL
+
+import (
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+// CreateSSBO uploads data to a new shader storage buffer object and binds it
+// to the given binding point (the `binding = N` layout qualifier in the
+// shader). data accepts the same shapes as resolveData: []float32, []uint32,
+// []mgl32.Vec2/Vec3/Vec4, []mgl32.Mat4, []byte, ... usage is one of
+// gl.STATIC_DRAW/gl.DYNAMIC_DRAW/gl.STREAM_DRAW.
+func CreateSSBO(binding uint32, data interface{}, usage uint32) (uint32, error) {
+	ptr, size, err := resolveData(data)
+	if err != nil {
+		return 0, err
+	}
+
+	var ssbo uint32
+	gl.GenBuffers(1, &ssbo)
+	gl.BindBuffer(gl.SHADER_STORAGE_BUFFER, ssbo)
+	gl.BufferData(gl.SHADER_STORAGE_BUFFER, size, ptr, usage)
+	gl.BindBufferBase(gl.SHADER_STORAGE_BUFFER, binding, ssbo)
+	gl.BindBuffer(gl.SHADER_STORAGE_BUFFER, 0)
+
+	return ssbo, nil
+}
+
+// UpdateSSBO replaces the contents of ssbo starting at offset bytes, without
+// reallocating the underlying storage.
+func UpdateSSBO(ssbo uint32, offset int, data interface{}) error {
+	ptr, size, err := resolveData(data)
+	if err != nil {
+		return err
+	}
+
+	gl.BindBuffer(gl.SHADER_STORAGE_BUFFER, ssbo)
+	gl.BufferSubData(gl.SHADER_STORAGE_BUFFER, offset, size, ptr)
+	gl.BindBuffer(gl.SHADER_STORAGE_BUFFER, 0)
+
+	return nil
+}
+
+// ReadSSBO reads size bytes back from ssbo starting at offset into dst,
+// which must be a pointer to a slice of one of resolveData's supported
+// element types (e.g. *[]float32).
+func ReadSSBO(ssbo uint32, offset, size int, dst interface{}) error {
+	ptr, err := resolveDataPtr(dst)
+	if err != nil {
+		return err
+	}
+
+	gl.BindBuffer(gl.SHADER_STORAGE_BUFFER, ssbo)
+	gl.GetBufferSubData(gl.SHADER_STORAGE_BUFFER, offset, size, ptr)
+	gl.BindBuffer(gl.SHADER_STORAGE_BUFFER, 0)
+
+	return nil
+}
+
+// CreateUBO uploads data to a new uniform buffer object and binds it to the
+// given binding point (the `binding = N` layout qualifier in the shader).
+func CreateUBO(binding uint32, data interface{}, usage uint32) (uint32, error) {
+	ptr, size, err := resolveData(data)
+	if err != nil {
+		return 0, err
+	}
+
+	var ubo uint32
+	gl.GenBuffers(1, &ubo)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, ubo)
+	gl.BufferData(gl.UNIFORM_BUFFER, size, ptr, usage)
+	gl.BindBufferBase(gl.UNIFORM_BUFFER, binding, ubo)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, 0)
+
+	return ubo, nil
+}
+
+// UpdateUBO replaces the contents of ubo starting at offset bytes, without
+// reallocating the underlying storage.
+func UpdateUBO(ubo uint32, offset int, data interface{}) error {
+	ptr, size, err := resolveData(data)
+	if err != nil {
+		return err
+	}
+
+	gl.BindBuffer(gl.UNIFORM_BUFFER, ubo)
+	gl.BufferSubData(gl.UNIFORM_BUFFER, offset, size, ptr)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, 0)
+
+	return nil
+}
+
+// Dispatch binds program, issues a compute dispatch of gx*gy*gz work groups,
+// and then issues glMemoryBarrier with the given barrier bits (e.g.
+// gl.SHADER_STORAGE_BARRIER_BIT|gl.BUFFER_UPDATE_BARRIER_BIT), so subsequent
+// draws/reads see the compute shader's writes. It does not block the CPU.
+func Dispatch(program uint32, gx, gy, gz uint32, barrier uint32) {
+	gl.UseProgram(program)
+	gl.DispatchCompute(gx, gy, gz)
+	gl.MemoryBarrier(barrier)
+}
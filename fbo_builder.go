@@ -0,0 +1,286 @@
+package mtOpenGL
+
+import (
+	"fmt"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+// Fbo is a framebuffer together with every attachment handle it owns, so
+// FreeFbo can clean all of them up. A non-zero Resolve points at a
+// single-sampled framebuffer of the same layout, only set when the builder
+// was configured WithSamples(n).WithResolve(true); blit into it with
+// glBlitFramebuffer to resolve MSAA before sampling.
+type Fbo struct {
+	Handle                   uint32
+	Width, Height            int32
+	Multisampling            bool
+	ColorTextures            []uint32
+	DepthTexture             uint32
+	DepthStencilRenderbuffer uint32
+	StencilRenderbuffer      uint32
+	Resolve                  *Fbo
+}
+
+// FboError is returned by FboBuilder.Build when glCheckFramebufferStatus
+// does not report GL_FRAMEBUFFER_COMPLETE.
+type FboError struct {
+	Status uint32
+}
+
+func (e *FboError) Error() string {
+	return fmt.Sprintf("framebuffer incomplete: status 0x%x", e.Status)
+}
+
+type fboColorAttachment struct {
+	internalFormat uint32
+	format         uint32
+	dataType       uint32
+}
+
+// FboBuilder chains the configuration of a framebuffer's attachments before
+// building it, replacing the hard-coded single-color/depth-texture shape of
+// CreateFbo/CreateLightFbo with support for N color attachments, a
+// renderbuffer-backed depth/stencil, and MSAA.
+type FboBuilder struct {
+	width, height int32
+	colors        []fboColorAttachment
+	depth         bool
+	depthStencil  bool
+	stencil       bool
+	samples       int32
+	mipmapLevels  int32
+	withResolve   bool
+}
+
+// NewFboBuilder starts building a framebuffer of the given size.
+func NewFboBuilder(width, height int32) *FboBuilder {
+	return &FboBuilder{width: width, height: height, mipmapLevels: 1}
+}
+
+// AddColor attaches a sampleable color texture with the given
+// internalFormat/format/dataType (e.g. gl.RGBA8, gl.RGBA, gl.UNSIGNED_BYTE).
+// Can be called multiple times for multiple render targets (MRT); the
+// resulting attachments are wired up via gl.DrawBuffers in attachment order.
+func (b *FboBuilder) AddColor(internalFormat, format, dataType uint32) *FboBuilder {
+	b.colors = append(b.colors, fboColorAttachment{internalFormat, format, dataType})
+	return b
+}
+
+// AddDepth attaches a sampleable depth texture (GL_DEPTH_COMPONENT32/FLOAT),
+// matching what CreateFbo/CreateLightFbo always attached.
+func (b *FboBuilder) AddDepth() *FboBuilder {
+	b.depth = true
+	return b
+}
+
+// AddDepthStencil attaches a combined depth/stencil renderbuffer
+// (GL_DEPTH24_STENCIL8). Renderbuffers are cheaper than textures when the
+// depth/stencil values never need to be sampled afterwards.
+func (b *FboBuilder) AddDepthStencil() *FboBuilder {
+	b.depthStencil = true
+	return b
+}
+
+// AddStencil attaches a stencil-only renderbuffer (GL_STENCIL_INDEX8).
+func (b *FboBuilder) AddStencil() *FboBuilder {
+	b.stencil = true
+	return b
+}
+
+// WithSamples enables MSAA with the given sample count for every attachment.
+func (b *FboBuilder) WithSamples(samples int32) *FboBuilder {
+	b.samples = samples
+	return b
+}
+
+// WithMipmaps allocates the given number of mipmap levels for every color
+// attachment texture. The levels are not generated by Build, and the count is
+// ignored for multisampled attachments.
+func (b *FboBuilder) WithMipmaps(levels int32) *FboBuilder {
+	b.mipmapLevels = levels
+	return b
+}
+
+// WithResolve additionally builds a single-sampled Fbo of the same layout,
+// available as the returned Fbo's Resolve field, to glBlitFramebuffer into
+// once the MSAA target has been rendered to. Only meaningful together with
+// WithSamples.
+func (b *FboBuilder) WithResolve(enabled bool) *FboBuilder {
+	b.withResolve = enabled
+	return b
+}
+
+// Build allocates every configured attachment, wires them into a new
+// framebuffer object, and checks glCheckFramebufferStatus, returning an
+// *FboError if the framebuffer isn't complete.
+func (b *FboBuilder) Build() (Fbo, error) {
+
+	multisampling := b.samples > 1
+	mipmapLevels := b.mipmapLevels
+	if mipmapLevels < 1 {
+		mipmapLevels = 1
+	}
+
+	texType := uint32(gl.TEXTURE_2D)
+	if multisampling {
+		texType = gl.TEXTURE_2D_MULTISAMPLE
+	}
+
+	var fbo Fbo
+	fbo.Width, fbo.Height = b.width, b.height
+	fbo.Multisampling = multisampling
+
+	gl.GenFramebuffers(1, &fbo.Handle)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, fbo.Handle)
+
+	var drawBuffers []uint32
+	for i, c := range b.colors {
+		tex := CreateTexture(b.width, b.height, c.internalFormat, c.format, c.dataType, multisampling, b.samples, mipmapLevels)
+		attachment := uint32(gl.COLOR_ATTACHMENT0 + i)
+		gl.FramebufferTexture2D(gl.FRAMEBUFFER, attachment, texType, tex, 0)
+		fbo.ColorTextures = append(fbo.ColorTextures, tex)
+		drawBuffers = append(drawBuffers, attachment)
+	}
+
+	if len(drawBuffers) == 0 {
+		gl.DrawBuffer(gl.NONE)
+		gl.ReadBuffer(gl.NONE)
+	} else {
+		gl.DrawBuffers(int32(len(drawBuffers)), &drawBuffers[0])
+	}
+
+	if b.depth {
+		fbo.DepthTexture = CreateTexture(b.width, b.height, gl.DEPTH_COMPONENT32, gl.DEPTH_COMPONENT, gl.FLOAT, multisampling, b.samples, 1)
+		gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, texType, fbo.DepthTexture, 0)
+	}
+
+	if b.depthStencil {
+		fbo.DepthStencilRenderbuffer = newRenderbuffer(gl.DEPTH24_STENCIL8, b.width, b.height, multisampling, b.samples)
+		gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.DEPTH_STENCIL_ATTACHMENT, gl.RENDERBUFFER, fbo.DepthStencilRenderbuffer)
+	}
+
+	if b.stencil {
+		fbo.StencilRenderbuffer = newRenderbuffer(gl.STENCIL_INDEX8, b.width, b.height, multisampling, b.samples)
+		gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.STENCIL_ATTACHMENT, gl.RENDERBUFFER, fbo.StencilRenderbuffer)
+	}
+
+	status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+
+	if status != gl.FRAMEBUFFER_COMPLETE {
+		FreeFbo(&fbo)
+		return Fbo{}, &FboError{Status: status}
+	}
+
+	if multisampling && b.withResolve {
+		resolveBuilder := &FboBuilder{width: b.width, height: b.height, colors: b.colors, depth: b.depth, depthStencil: b.depthStencil, stencil: b.stencil, mipmapLevels: mipmapLevels}
+		resolve, err := resolveBuilder.Build()
+		if err != nil {
+			FreeFbo(&fbo)
+			return Fbo{}, err
+		}
+		fbo.Resolve = &resolve
+	}
+
+	return fbo, nil
+}
+
+func newRenderbuffer(internalFormat uint32, width, height int32, multisampling bool, samples int32) uint32 {
+	var rbo uint32
+	gl.GenRenderbuffers(1, &rbo)
+	gl.BindRenderbuffer(gl.RENDERBUFFER, rbo)
+	if multisampling {
+		gl.RenderbufferStorageMultisample(gl.RENDERBUFFER, samples, internalFormat, width, height)
+	} else {
+		gl.RenderbufferStorage(gl.RENDERBUFFER, internalFormat, width, height)
+	}
+	gl.BindRenderbuffer(gl.RENDERBUFFER, 0)
+	return rbo
+}
+
+// FreeFbo deletes every attachment Fbo owns (including a Resolve target, if
+// any) along with the framebuffer object itself, and zeroes fbo out.
+func FreeFbo(fbo *Fbo) {
+	for _, tex := range fbo.ColorTextures {
+		t := tex
+		gl.DeleteTextures(1, &t)
+	}
+	if fbo.DepthTexture != 0 {
+		gl.DeleteTextures(1, &fbo.DepthTexture)
+	}
+	if fbo.DepthStencilRenderbuffer != 0 {
+		gl.DeleteRenderbuffers(1, &fbo.DepthStencilRenderbuffer)
+	}
+	if fbo.StencilRenderbuffer != 0 {
+		gl.DeleteRenderbuffers(1, &fbo.StencilRenderbuffer)
+	}
+	if fbo.Resolve != nil {
+		FreeFbo(fbo.Resolve)
+	}
+	if fbo.Handle != 0 {
+		gl.DeleteFramebuffers(1, &fbo.Handle)
+	}
+
+	*fbo = Fbo{}
+}
+
+// CreateLightFbo is a thin wrapper around FboBuilder for the light-pass FBO
+// shape: a single RG32F color attachment plus a sampleable depth texture.
+// Some internal format changes, like only having the RG channels but with
+// higher 32F precision.
+func CreateLightFbo(colorTex, depthTex *uint32, width, height int32, multisampling bool, samples int32) uint32 {
+
+	builder := NewFboBuilder(width, height).AddColor(gl.RG32F, gl.RG, gl.FLOAT).AddDepth()
+	if multisampling {
+		builder.WithSamples(samples)
+	}
+
+	fbo, err := builder.Build()
+	if err != nil {
+		fmt.Printf("CreateLightFbo failed: %v\n", err)
+		return 0
+	}
+
+	if colorTex != nil {
+		*colorTex = fbo.ColorTextures[0]
+	}
+	if depthTex != nil {
+		*depthTex = fbo.DepthTexture
+	}
+
+	return fbo.Handle
+}
+
+// CreateFbo is a thin wrapper around FboBuilder for the general-purpose FBO
+// shape: a single color attachment (8-bit or floating point) plus a
+// sampleable depth texture.
+func CreateFbo(colorTex, depthTex *uint32, width, height int32, multisampling bool, samples int32, isFloatingPoint bool, mipmapLevels int32) uint32 {
+
+	var intFormat uint32 = gl.RGBA8
+	var dataType uint32 = gl.UNSIGNED_BYTE
+	if isFloatingPoint {
+		intFormat = gl.RGBA32F
+		dataType = gl.FLOAT
+	}
+
+	builder := NewFboBuilder(width, height).AddColor(intFormat, gl.RGBA, dataType).AddDepth().WithMipmaps(mipmapLevels)
+	if multisampling {
+		builder.WithSamples(samples)
+	}
+
+	fbo, err := builder.Build()
+	if err != nil {
+		fmt.Printf("CreateFbo failed: %v\n", err)
+		return 0
+	}
+
+	if colorTex != nil {
+		*colorTex = fbo.ColorTextures[0]
+	}
+	if depthTex != nil {
+		*depthTex = fbo.DepthTexture
+	}
+
+	return fbo.Handle
+}
@@ -8,7 +8,6 @@ import (
 	"io"
 	"os"
 	"strings"
-	"unsafe"
 
 	"github.com/go-gl/gl/v4.5-core/gl"
 	"github.com/go-gl/mathgl/mgl32"
@@ -21,6 +20,9 @@ type MeshBuffer struct {
 	VertexCount  int32
 	IndexBuffer  uint32
 	IndexCount   int32
+	// IndexType is gl.UNSIGNED_SHORT or gl.UNSIGNED_INT, set whenever
+	// IndexCount > 0, so callers know which type to pass to glDrawElements.
+	IndexType uint32
 }
 
 type ImageTexture struct {
@@ -46,7 +48,7 @@ func compileShader(source string, shaderType uint32) (uint32, error) {
 		log := strings.Repeat("\x00", int(logLength+1))
 		gl.GetShaderInfoLog(shader, logLength, nil, gl.Str(log))
 
-		return 0, fmt.Errorf("failed to compile %v: %v", source, log)
+		return 0, fmt.Errorf("%v", log)
 	}
 
 	return shader, nil
@@ -65,134 +67,6 @@ func readFile(name string) (string, error) {
 	return string(buf.Bytes()), nil
 }
 
-// Mostly taken from the Demo. But compiling and linking shaders
-// just should be done like this anyways.
-func NewProgram(vertexShaderName, geometryShaderName, tessControlShaderName, tessEvalShaderName, fragmentShaderName string) (uint32, error) {
-	useTessellationShader := tessControlShaderName != "" && tessEvalShaderName != ""
-	useGeometryShader := geometryShaderName != ""
-
-	vertexShaderSource, err := readFile(vertexShaderName)
-	if err != nil {
-		return 0, err
-	}
-	vertexShader, err := compileShader(vertexShaderSource, gl.VERTEX_SHADER)
-	if err != nil {
-		fmt.Printf("error: %v\n", err)
-		return 0, err
-	}
-
-	// Compile Tessellation shader
-	var tessControlShader, tessEvalShader uint32
-	if useTessellationShader {
-		tessControlSource, err := readFile(tessControlShaderName)
-		if err != nil {
-			return 0, err
-		}
-		tessControlShader, err = compileShader(tessControlSource, gl.TESS_CONTROL_SHADER)
-		if err != nil {
-			return 0, err
-		}
-		tessEvalSource, err := readFile(tessEvalShaderName)
-		if err != nil {
-			return 0, err
-		}
-		tessEvalShader, err = compileShader(tessEvalSource, gl.TESS_EVALUATION_SHADER)
-		if err != nil {
-			return 0, err
-		}
-	}
-
-	fragmentShaderSource, err := readFile(fragmentShaderName)
-	if err != nil {
-		return 0, err
-	}
-	fragmentShader, err := compileShader(fragmentShaderSource, gl.FRAGMENT_SHADER)
-	if err != nil {
-		return 0, err
-	}
-
-	var geometryShader uint32
-	if useGeometryShader {
-		geometryShaderSource, err := readFile(geometryShaderName)
-		if err != nil {
-			return 0, err
-		}
-		geometryShader, err = compileShader(geometryShaderSource, gl.GEOMETRY_SHADER)
-		if err != nil {
-			return 0, err
-		}
-	}
-
-	program := gl.CreateProgram()
-
-	gl.AttachShader(program, vertexShader)
-	if useTessellationShader {
-		gl.AttachShader(program, tessControlShader)
-		gl.AttachShader(program, tessEvalShader)
-	}
-	gl.AttachShader(program, fragmentShader)
-	if useGeometryShader {
-		gl.AttachShader(program, geometryShader)
-	}
-	gl.LinkProgram(program)
-
-	var status int32
-	gl.GetProgramiv(program, gl.LINK_STATUS, &status)
-	if status == gl.FALSE {
-		var logLength int32
-		gl.GetProgramiv(program, gl.INFO_LOG_LENGTH, &logLength)
-
-		log := strings.Repeat("\x00", int(logLength+1))
-		gl.GetProgramInfoLog(program, logLength, nil, gl.Str(log))
-
-		return 0, fmt.Errorf("failed to link program: %v", log)
-	}
-
-	gl.DeleteShader(vertexShader)
-	if useTessellationShader {
-		gl.DeleteShader(tessControlShader)
-		gl.DeleteShader(tessEvalShader)
-	}
-	gl.DeleteShader(fragmentShader)
-	if useGeometryShader {
-		gl.DeleteShader(geometryShader)
-	}
-
-	return program, nil
-}
-
-func NewComputeProgram(computeShaderName string) (uint32, error) {
-
-	computeShaderSource, err := readFile(computeShaderName)
-	if err != nil {
-		return 0, err
-	}
-	computeShader, err := compileShader(computeShaderSource, gl.COMPUTE_SHADER)
-	if err != nil {
-		return 0, err
-	}
-	program := gl.CreateProgram()
-
-	gl.AttachShader(program, computeShader)
-	gl.LinkProgram(program)
-
-	var status int32
-	gl.GetProgramiv(program, gl.LINK_STATUS, &status)
-	if status == gl.FALSE {
-		var logLength int32
-		gl.GetProgramiv(program, gl.INFO_LOG_LENGTH, &logLength)
-
-		log := strings.Repeat("\x00", int(logLength+1))
-		gl.GetProgramInfoLog(program, logLength, nil, gl.Str(log))
-
-		return 0, fmt.Errorf("failed to link program: %v", log)
-	}
-
-	gl.DeleteShader(computeShader)
-
-	return program, nil
-}
-
 func CreateTexture(width, height int32, internalFormat, format, internalType uint32, multisampling bool, samples, mipmapLevels int32) uint32 {
 
 	var texType uint32 = gl.TEXTURE_2D
@@ -248,6 +122,13 @@ func CreateImageTexture(imageName string, isRepeating bool) ImageTexture {
 
 }
 
+// CreateFboWithExistingTextures attaches caller-owned textures (e.g. ones
+// ping-ponged between two framebuffers, or shared with another FBO) to a new
+// framebuffer. This is a different use case from FboBuilder, which always
+// creates and owns the textures it attaches, so it is kept as its own
+// function rather than folded into the builder; it now reports an incomplete
+// framebuffer the same way CreateFbo/CreateLightFbo report failure, by
+// logging and returning 0, to keep its signature backward compatible.
 func CreateFboWithExistingTextures(colorTex, depthTex *uint32, texType uint32) uint32 {
 
 	var fbo uint32
@@ -261,110 +142,41 @@ func CreateFboWithExistingTextures(colorTex, depthTex *uint32, texType uint32) u
 		gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, texType, *depthTex, 0)
 	}
 
+	status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER)
 	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
 
-	return fbo
-}
-
-// Some internal format changes, like only having the RG channels but with higher 32F precision.
-func CreateLightFbo(colorTex, depthTex *uint32, width, height int32, multisampling bool, samples int32) uint32 {
-
-	if colorTex != nil {
-		*colorTex = CreateTexture(width, height, gl.RG32F, gl.RG, gl.FLOAT, multisampling, samples, 1)
-	}
-	if depthTex != nil {
-		*depthTex = CreateTexture(width, height, gl.DEPTH_COMPONENT32, gl.DEPTH_COMPONENT, gl.FLOAT, multisampling, samples, 1)
+	if status != gl.FRAMEBUFFER_COMPLETE {
+		fmt.Printf("CreateFboWithExistingTextures failed: %v\n", &FboError{Status: status})
+		gl.DeleteFramebuffers(1, &fbo)
+		return 0
 	}
 
-	var texType uint32 = gl.TEXTURE_2D
-	if multisampling {
-		texType = gl.TEXTURE_2D_MULTISAMPLE
-	}
-
-	return CreateFboWithExistingTextures(colorTex, depthTex, texType)
-}
-
-func CreateFbo(colorTex, depthTex *uint32, width, height int32, multisampling bool, samples int32, isFloatingPoint bool, mipmapLevels int32) uint32 {
-
-	var intFormat uint32 = uint32(gl.RGBA8)
-	var format uint32 = uint32(gl.RGBA)
-	var ttype uint32 = uint32(gl.UNSIGNED_BYTE)
-
-	if isFloatingPoint {
-		intFormat = gl.RGBA32F
-		ttype = gl.FLOAT
-	}
-
-	if colorTex != nil {
-		*colorTex = CreateTexture(width, height, intFormat, format, ttype, multisampling, samples, mipmapLevels)
-	}
-	if depthTex != nil {
-		*depthTex = CreateTexture(width, height, gl.DEPTH_COMPONENT32, gl.DEPTH_COMPONENT, gl.FLOAT, multisampling, samples, 1)
-	}
-
-	var texType uint32 = gl.TEXTURE_2D
-	if multisampling {
-		texType = gl.TEXTURE_2D_MULTISAMPLE
-	}
-
-	return CreateFboWithExistingTextures(colorTex, depthTex, texType)
+	return fbo
 }
 
+// GenerateBufferFromTriangles2D is a thin wrapper around
+// GenerateBufferFromLayout for the common case of plain 2D position-only
+// triangle data.
 func GenerateBufferFromTriangles2D(bufferObject *MeshBuffer, points []mgl32.Vec2) {
 
 	if len(points) < 3 {
 		return
 	}
 
-	var tmpM mgl32.Vec2
-	stride := int32(unsafe.Sizeof(tmpM))
-
-	gl.GenBuffers(1, &bufferObject.ArrayBuffer)
-	gl.BindBuffer(gl.ARRAY_BUFFER, bufferObject.ArrayBuffer)
-	gl.BufferData(gl.ARRAY_BUFFER, int(stride)*len(points), gl.Ptr(points), gl.STATIC_DRAW)
-
-	gl.GenVertexArrays(1, &bufferObject.VertexBuffer)
-	gl.BindVertexArray(bufferObject.VertexBuffer)
-
-	gl.EnableVertexAttribArray(0)
-	gl.VertexAttribPointer(0, 2, gl.FLOAT, false, stride, gl.PtrOffset(0))
-
-	bufferObject.VertexCount = int32(len(points))
-
-	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+	layout := NewVertexLayout(VertexAttribute{Index: 0, Size: 2, Type: gl.FLOAT})
+	GenerateBufferFromLayout(bufferObject, points, layout, nil, gl.STATIC_DRAW)
 }
 
+// GenerateBufferFromLines2D is a thin wrapper around GenerateBufferFromLayout
+// for the common case of plain 2D position-only line data with indices.
 func GenerateBufferFromLines2D(bufferObject *MeshBuffer, points []mgl32.Vec2, indices []uint32) {
 
 	if len(points) < 2 || len(indices) < 2 {
 		return
 	}
 
-	var tmpM mgl32.Vec2
-	stride := int32(unsafe.Sizeof(tmpM))
-	var ui uint32
-	uiStride := int(unsafe.Sizeof(ui))
-
-	gl.GenBuffers(1, &bufferObject.ArrayBuffer)
-	gl.BindBuffer(gl.ARRAY_BUFFER, bufferObject.ArrayBuffer)
-	gl.BufferData(gl.ARRAY_BUFFER, int(stride)*len(points), gl.Ptr(points), gl.STATIC_DRAW)
-
-	gl.GenVertexArrays(1, &bufferObject.VertexBuffer)
-	gl.BindVertexArray(bufferObject.VertexBuffer)
-
-	gl.EnableVertexAttribArray(0)
-	gl.VertexAttribPointer(0, 2, gl.FLOAT, false, stride, gl.PtrOffset(0))
-
-	bufferObject.VertexCount = int32(len(points))
-
-	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
-
-	gl.GenBuffers(1, &bufferObject.IndexBuffer)
-	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, bufferObject.IndexBuffer)
-	gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, uiStride*len(indices), gl.Ptr(indices), gl.STATIC_DRAW)
-	gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, 0)
-	bufferObject.IndexCount = int32(len(indices))
-
+	layout := NewVertexLayout(VertexAttribute{Index: 0, Size: 2, Type: gl.FLOAT})
+	GenerateBufferFromLayout(bufferObject, points, layout, indices, gl.STATIC_DRAW)
 }
 
 func FreeGLBuffer(buffer *MeshBuffer) {
@@ -381,5 +193,7 @@ func FreeGLBuffer(buffer *MeshBuffer) {
 		gl.DeleteBuffers(1, &buffer.IndexBuffer)
 		buffer.VertexCount = 0
 		buffer.IndexBuffer = 0
+		buffer.IndexCount = 0
+		buffer.IndexType = 0
 	}
 }
@@ -0,0 +1,341 @@
+package mtOpenGL
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-gl/gl/v4.5-core/gl"
+)
+
+// ShaderStage pairs a GL shader stage type with the ShaderSource it is
+// compiled from, so a Program can be recompiled and relinked in place when
+// that source's backing file changes on disk.
+type ShaderStage struct {
+	Type   uint32
+	Source ShaderSource
+}
+
+// Program is a linked GL program together with the shader stages it was
+// built from. Keeping the stages around lets WatchAndReload recompile and
+// relink the program whenever one of its source files changes.
+type Program struct {
+	Handle uint32
+	Stages []ShaderStage
+}
+
+func compileStage(stage ShaderStage) (uint32, error) {
+	code, sm, err := stage.Source.Resolve()
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve %v: %v", stage.Source.displayName(), err)
+	}
+
+	shader, err := compileShader(code, stage.Type)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compile %v: %v", stage.Source.displayName(), rewriteLog(err.Error(), sm))
+	}
+
+	return shader, nil
+}
+
+func linkProgram(stages []ShaderStage) (uint32, error) {
+	program := gl.CreateProgram()
+
+	var compiled []uint32
+	for _, stage := range stages {
+		shader, err := compileStage(stage)
+		if err != nil {
+			for _, s := range compiled {
+				gl.DeleteShader(s)
+			}
+			return 0, err
+		}
+		gl.AttachShader(program, shader)
+		compiled = append(compiled, shader)
+	}
+
+	gl.LinkProgram(program)
+
+	var status int32
+	gl.GetProgramiv(program, gl.LINK_STATUS, &status)
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetProgramiv(program, gl.INFO_LOG_LENGTH, &logLength)
+
+		log := strings.Repeat("\x00", int(logLength+1))
+		gl.GetProgramInfoLog(program, logLength, nil, gl.Str(log))
+
+		for _, s := range compiled {
+			gl.DeleteShader(s)
+		}
+		gl.DeleteProgram(program)
+
+		return 0, fmt.Errorf("failed to link program: %v", log)
+	}
+
+	for _, s := range compiled {
+		gl.DeleteShader(s)
+	}
+
+	return program, nil
+}
+
+// NewProgramFromSources compiles and links a Program from ShaderSource
+// values, which may come from files, strings, readers or an embed.FS and may
+// use #include/#pragma once/WithDefines. geometrySrc and the tessellation
+// pair are optional: pass the zero ShaderSource{} to omit a stage.
+func NewProgramFromSources(vertexSrc, geometrySrc, tessControlSrc, tessEvalSrc, fragmentSrc ShaderSource) (Program, error) {
+	useTessellationShader := !tessControlSrc.isZero() && !tessEvalSrc.isZero()
+	useGeometryShader := !geometrySrc.isZero()
+
+	stages := []ShaderStage{{Type: gl.VERTEX_SHADER, Source: vertexSrc}}
+	if useTessellationShader {
+		stages = append(stages,
+			ShaderStage{Type: gl.TESS_CONTROL_SHADER, Source: tessControlSrc},
+			ShaderStage{Type: gl.TESS_EVALUATION_SHADER, Source: tessEvalSrc},
+		)
+	}
+	stages = append(stages, ShaderStage{Type: gl.FRAGMENT_SHADER, Source: fragmentSrc})
+	if useGeometryShader {
+		stages = append(stages, ShaderStage{Type: gl.GEOMETRY_SHADER, Source: geometrySrc})
+	}
+
+	handle, err := linkProgram(stages)
+	if err != nil {
+		return Program{}, err
+	}
+
+	return Program{Handle: handle, Stages: stages}, nil
+}
+
+// NewComputeProgramFromSources compiles and links a compute Program from a
+// ShaderSource. See NewProgramFromSources for what a ShaderSource can be.
+func NewComputeProgramFromSources(computeSrc ShaderSource) (Program, error) {
+	stages := []ShaderStage{{Type: gl.COMPUTE_SHADER, Source: computeSrc}}
+
+	handle, err := linkProgram(stages)
+	if err != nil {
+		return Program{}, err
+	}
+
+	return Program{Handle: handle, Stages: stages}, nil
+}
+
+func fileSourceOrZero(name string) ShaderSource {
+	if name == "" {
+		return ShaderSource{}
+	}
+	return ShaderSourceFromFile(name)
+}
+
+// NewProgram is a convenience wrapper around NewProgramFromSources for the
+// common case of plain shader files on disk. Mostly taken from the Demo.
+// But compiling and linking shaders just should be done like this anyways.
+func NewProgram(vertexShaderName, geometryShaderName, tessControlShaderName, tessEvalShaderName, fragmentShaderName string) (Program, error) {
+	return NewProgramFromSources(
+		ShaderSourceFromFile(vertexShaderName),
+		fileSourceOrZero(geometryShaderName),
+		fileSourceOrZero(tessControlShaderName),
+		fileSourceOrZero(tessEvalShaderName),
+		ShaderSourceFromFile(fragmentShaderName),
+	)
+}
+
+// NewComputeProgram is a convenience wrapper around
+// NewComputeProgramFromSources for the common case of a plain shader file on
+// disk.
+func NewComputeProgram(computeShaderName string) (Program, error) {
+	return NewComputeProgramFromSources(ShaderSourceFromFile(computeShaderName))
+}
+
+// ReloadErrorFunc is called whenever a shader stage fails to recompile or a
+// program fails to relink during a hot-reload processed by
+// ReloadWatcher.ProcessPendingReloads. The affected Program keeps its
+// previous (still working) Handle.
+type ReloadErrorFunc func(program *Program, err error)
+
+// ReloadWatcher watches the source files backing a set of Programs for
+// changes. The filesystem watch runs on its own goroutine, but it only ever
+// flags a Program as needing a reload: no GL call is made there. GL has no
+// current context on a goroutine other than the one that created it, so the
+// actual recompile/relink/swap must happen on the render thread, via
+// ProcessPendingReloads.
+type ReloadWatcher struct {
+	mu       sync.Mutex
+	pending  map[*Program]bool
+	programs []*Program
+	watcher  *fsnotify.Watcher
+	modTimes map[string]time.Time
+	done     chan struct{}
+}
+
+// WatchAndReload starts watching the source files backing each given
+// Program. It prefers fsnotify for instant, event-driven change detection
+// and falls back to polling mtime every 500ms if a filesystem watcher could
+// not be created (e.g. no inotify instances left). If adding a watch on a
+// source file's directory fails, the watcher is closed and that error is
+// returned; there is no polling fallback in that case.
+//
+// The caller's render loop must call ProcessPendingReloads once per frame
+// (or on whatever cadence is appropriate) to actually recompile, relink and
+// swap in any Program flagged by the watcher since the last call. Call
+// Stop once the caller is done with hot-reloading.
+func WatchAndReload(programs ...*Program) (*ReloadWatcher, error) {
+	w := &ReloadWatcher{
+		pending:  map[*Program]bool{},
+		programs: programs,
+		done:     make(chan struct{}),
+	}
+
+	watcher, watchErr := fsnotify.NewWatcher()
+	if watchErr != nil {
+		w.modTimes = map[string]time.Time{}
+		for _, p := range programs {
+			for _, stage := range p.Stages {
+				path, ok := stage.Source.filePath()
+				if !ok {
+					continue
+				}
+				if info, err := os.Stat(path); err == nil {
+					w.modTimes[path] = info.ModTime()
+				}
+			}
+		}
+		go w.pollLoop()
+		return w, nil
+	}
+
+	watchedDirs := map[string]bool{}
+	for _, p := range programs {
+		for _, stage := range p.Stages {
+			path, ok := stage.Source.filePath()
+			if !ok {
+				continue
+			}
+			dir := filepath.Dir(path)
+			if watchedDirs[dir] {
+				continue
+			}
+			if err := watcher.Add(dir); err != nil {
+				watcher.Close()
+				return nil, err
+			}
+			watchedDirs[dir] = true
+		}
+	}
+
+	w.watcher = watcher
+	go w.watchLoop()
+
+	return w, nil
+}
+
+func (w *ReloadWatcher) watchLoop() {
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			w.markPending(event.Name)
+		case <-w.watcher.Errors:
+			// Ignored: a watcher error doesn't invalidate the programs
+			// we've already linked, so we just keep watching.
+		case <-w.done:
+			w.watcher.Close()
+			return
+		}
+	}
+}
+
+func (w *ReloadWatcher) pollLoop() {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.mu.Lock()
+			for _, p := range w.programs {
+				for _, stage := range p.Stages {
+					path, ok := stage.Source.filePath()
+					if !ok {
+						continue
+					}
+					info, err := os.Stat(path)
+					if err != nil {
+						continue
+					}
+					if last, ok := w.modTimes[path]; !ok || info.ModTime().After(last) {
+						w.modTimes[path] = info.ModTime()
+						w.pending[p] = true
+					}
+				}
+			}
+			w.mu.Unlock()
+		case <-w.done:
+			return
+		}
+	}
+}
+
+func (w *ReloadWatcher) markPending(changedPath string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, p := range w.programs {
+		for _, stage := range p.Stages {
+			path, ok := stage.Source.filePath()
+			if ok && filepath.Clean(path) == filepath.Clean(changedPath) {
+				w.pending[p] = true
+				break
+			}
+		}
+	}
+}
+
+// ProcessPendingReloads recompiles and relinks every Program flagged by the
+// watcher since the last call, swapping in the new Handle only if linking
+// succeeds; on failure the previous Handle stays bound and onError is called
+// with the compile/link error so the caller can surface it (log it, show it
+// in an overlay, ...). Must be called from the thread that owns the GL
+// context.
+func (w *ReloadWatcher) ProcessPendingReloads(onError ReloadErrorFunc) {
+	w.mu.Lock()
+	due := make([]*Program, 0, len(w.pending))
+	for p := range w.pending {
+		due = append(due, p)
+	}
+	w.pending = map[*Program]bool{}
+	w.mu.Unlock()
+
+	for _, p := range due {
+		reloadProgram(p, onError)
+	}
+}
+
+// Stop tears down the watch goroutine.
+func (w *ReloadWatcher) Stop() {
+	close(w.done)
+}
+
+func reloadProgram(p *Program, onError ReloadErrorFunc) {
+	newHandle, err := linkProgram(p.Stages)
+	if err != nil {
+		if onError != nil {
+			onError(p, err)
+		}
+		return
+	}
+
+	old := p.Handle
+	p.Handle = newHandle
+	gl.DeleteProgram(old)
+}
@@ -0,0 +1,268 @@
+package mtOpenGL
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// shaderOrigin records where a ShaderSource's raw text comes from when it
+// supports #include resolution: either a path on disk, or a path inside an
+// fs.FS (e.g. an embed.FS).
+type shaderOrigin struct {
+	path string
+	fsys fs.FS
+}
+
+// ShaderSource is a GLSL source that may come from a file, a plain string,
+// an io.Reader, or an embed.FS entry, and that may reference other sources
+// via `#include "path"`. Construct one with ShaderSourceFromFile,
+// ShaderSourceFromString, ShaderSourceFromReader or ShaderSourceFromFS.
+type ShaderSource struct {
+	name    string
+	text    string
+	origin  *shaderOrigin
+	defines string
+}
+
+// ShaderSourceFromFile builds a ShaderSource that reads its text from path,
+// resolving any #include directives relative to path's directory.
+func ShaderSourceFromFile(path string) ShaderSource {
+	return ShaderSource{name: path, origin: &shaderOrigin{path: path}}
+}
+
+// ShaderSourceFromFS builds a ShaderSource that reads its text from path
+// within fsys (typically an embed.FS), resolving any #include directives
+// relative to path's directory inside fsys.
+func ShaderSourceFromFS(fsys fs.FS, path string) ShaderSource {
+	return ShaderSource{name: path, origin: &shaderOrigin{path: path, fsys: fsys}}
+}
+
+// ShaderSourceFromString builds a ShaderSource from an in-memory string.
+// name is only used for diagnostics. #include is not supported, since a
+// plain string has no directory to resolve relative paths against.
+func ShaderSourceFromString(name, src string) ShaderSource {
+	return ShaderSource{name: name, text: src}
+}
+
+// ShaderSourceFromReader drains r and builds a ShaderSource from its
+// contents. name is only used for diagnostics. Like ShaderSourceFromString,
+// #include is not supported.
+func ShaderSourceFromReader(name string, r io.Reader) (ShaderSource, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return ShaderSource{}, err
+	}
+	return ShaderSource{name: name, text: string(data)}, nil
+}
+
+// WithDefines returns a copy of s that has defines injected as a block
+// immediately after the `#version` line when the resolved source starts with
+// one (GLSL requires #version to stay the first line of the compiled source),
+// or at the very top otherwise. Useful for compiling shader variants from the
+// same source.
+func (s ShaderSource) WithDefines(defines string) ShaderSource {
+	s.defines = defines
+	return s
+}
+
+func (s ShaderSource) isZero() bool {
+	return s.name == "" && s.text == "" && s.origin == nil
+}
+
+func (s ShaderSource) displayName() string {
+	if s.name != "" {
+		return s.name
+	}
+	return "<string>"
+}
+
+// filePath returns the on-disk path backing s, if any, so callers like
+// WatchAndReload can set up a file watch for it.
+func (s ShaderSource) filePath() (string, bool) {
+	if s.origin == nil || s.origin.fsys != nil {
+		return "", false
+	}
+	return s.origin.path, true
+}
+
+func (s ShaderSource) rawText() (string, error) {
+	if s.origin == nil {
+		return s.text, nil
+	}
+	if s.origin.fsys != nil {
+		data, err := fs.ReadFile(s.origin.fsys, s.origin.path)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+	return readFile(s.origin.path)
+}
+
+// sourceMapLine records which file:line of the original (un-flattened)
+// sources produced a given line of the resolved, #include-expanded source,
+// so compile errors can be rewritten back to where the offending line
+// actually lives.
+type sourceMapLine struct {
+	file string
+	line int
+}
+
+// Resolve flattens s by recursively expanding #include directives, honoring
+// #pragma once, and returns the final GLSL source together with a line-by-line
+// source map.
+func (s ShaderSource) Resolve() (string, []sourceMapLine, error) {
+	emitted := map[string]bool{}
+	var sm []sourceMapLine
+
+	body, _, err := s.expand(emitted, &sm)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if s.defines != "" {
+		body, sm = injectDefines(body, sm, s.defines, s.displayName())
+	}
+
+	return body, sm, nil
+}
+
+func (s ShaderSource) expand(emitted map[string]bool, sm *[]sourceMapLine) (string, bool, error) {
+	raw, err := s.rawText()
+	if err != nil {
+		return "", false, err
+	}
+
+	name := s.displayName()
+	lines := strings.Split(raw, "\n")
+
+	var out strings.Builder
+	pragmaOnce := false
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		if trimmed == "#pragma once" {
+			pragmaOnce = true
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "#include") {
+			incPath, ok := parseIncludePath(trimmed)
+			if !ok {
+				return "", false, fmt.Errorf("%v:%v: malformed #include directive", name, i+1)
+			}
+
+			childOrigin, key, err := s.resolveIncludePath(incPath)
+			if err != nil {
+				return "", false, fmt.Errorf("%v:%v: %v", name, i+1, err)
+			}
+			if emitted[key] {
+				continue
+			}
+
+			child := ShaderSource{name: incPath, origin: childOrigin}
+			childBody, childPragmaOnce, err := child.expand(emitted, sm)
+			if err != nil {
+				return "", false, err
+			}
+			if childPragmaOnce {
+				emitted[key] = true
+			}
+
+			out.WriteString(childBody)
+			continue
+		}
+
+		out.WriteString(line)
+		out.WriteString("\n")
+		*sm = append(*sm, sourceMapLine{file: name, line: i + 1})
+	}
+
+	return out.String(), pragmaOnce, nil
+}
+
+func (s ShaderSource) resolveIncludePath(incPath string) (*shaderOrigin, string, error) {
+	if s.origin == nil {
+		return nil, "", fmt.Errorf("cannot resolve #include %q: %q has no file context", incPath, s.displayName())
+	}
+	if s.origin.fsys != nil {
+		joined := path.Join(path.Dir(s.origin.path), incPath)
+		return &shaderOrigin{path: joined, fsys: s.origin.fsys}, "fs:" + joined, nil
+	}
+	joined := filepath.Join(filepath.Dir(s.origin.path), incPath)
+	return &shaderOrigin{path: joined}, "file:" + joined, nil
+}
+
+func parseIncludePath(line string) (string, bool) {
+	start := strings.IndexByte(line, '"')
+	if start < 0 {
+		return "", false
+	}
+	end := strings.IndexByte(line[start+1:], '"')
+	if end < 0 {
+		return "", false
+	}
+	return line[start+1 : start+1+end], true
+}
+
+// injectDefines inserts defines right after the first line of src when that
+// line is a `#version` directive, so it remains the first line of the
+// compiled source, as GLSL requires; otherwise defines are prepended to src
+// (a `#version` further down is not searched for). It also
+// inserts a matching placeholder entry into sm for each injected line, so
+// sm stays aligned with the resulting source's line numbers and rewriteLog
+// keeps mapping driver errors back to the right file:line.
+func injectDefines(src string, sm []sourceMapLine, defines, name string) (string, []sourceMapLine) {
+	idx := strings.IndexByte(src, '\n')
+	firstLine := src
+	rest := ""
+	if idx >= 0 {
+		firstLine = src[:idx]
+		rest = src[idx+1:]
+	}
+
+	defineLines := strings.Split(defines, "\n")
+	placeholders := make([]sourceMapLine, len(defineLines))
+	for i := range placeholders {
+		placeholders[i] = sourceMapLine{file: name, line: 0}
+	}
+
+	if !strings.HasPrefix(strings.TrimSpace(firstLine), "#version") {
+		return defines + "\n" + src, append(placeholders, sm...)
+	}
+
+	newSM := make([]sourceMapLine, 0, len(sm)+len(placeholders))
+	if len(sm) > 0 {
+		newSM = append(newSM, sm[0])
+	}
+	newSM = append(newSM, placeholders...)
+	if len(sm) > 1 {
+		newSM = append(newSM, sm[1:]...)
+	}
+
+	return firstLine + "\n" + defines + "\n" + rest, newSM
+}
+
+var glslLogLineRe = regexp.MustCompile(`0[:(](\d+)\)?`)
+
+// rewriteLog rewrites `0:N` / `0(N)` driver-reported line numbers (both
+// forms show up depending on vendor) in a shader compile log back to the
+// file:line of the original, un-flattened source using sm.
+func rewriteLog(log string, sm []sourceMapLine) string {
+	return glslLogLineRe.ReplaceAllStringFunc(log, func(m string) string {
+		match := glslLogLineRe.FindStringSubmatch(m)
+		lineNum, err := strconv.Atoi(match[1])
+		if err != nil || lineNum < 1 || lineNum > len(sm) {
+			return m
+		}
+		entry := sm[lineNum-1]
+		return fmt.Sprintf("%v:%v", entry.file, entry.line)
+	})
+}
@@ -0,0 +1,290 @@
+package mtOpenGL
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"os"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+	"github.com/mdouchement/hdr"
+	_ "github.com/mdouchement/hdr/codec/openexr"
+	_ "github.com/mdouchement/hdr/codec/rgbe"
+)
+
+// glTextureMaxAnisotropy is GL_TEXTURE_MAX_ANISOTROPY, the token shared by
+// both EXT_texture_filter_anisotropic and the promoted ARB/core-4.6 form
+// (same value in the registry, 0x84FE). Hard-coded rather than referencing a
+// gl.TEXTURE_MAX_ANISOTROPY* constant, since which spelling a given go-gl
+// binding exports depends on the GL version it was generated against and
+// isn't worth gambling a compile failure on.
+const glTextureMaxAnisotropy = 0x84FE
+
+func applyAnisotropy(texType uint32, anisotropy float32) {
+	if anisotropy <= 1 {
+		return
+	}
+	gl.TexParameterf(texType, glTextureMaxAnisotropy, anisotropy)
+}
+
+// CreateImageTextureEx is CreateImageTexture with mipmap generation and
+// anisotropic filtering knobs: mipmapLevels > 1 enables mipmapping,
+// anisotropy > 1 sets GL_TEXTURE_MAX_ANISOTROPY_EXT.
+func CreateImageTextureEx(imageName string, isRepeating bool, mipmapLevels int32, anisotropy float32) ImageTexture {
+
+	var imageTexture ImageTexture
+
+	img, err := LoadImage(imageName)
+	if err != nil {
+		fmt.Printf("Image load failed: %v.\n", err)
+	}
+
+	var textureWrap int32 = gl.CLAMP_TO_EDGE
+	if isRepeating {
+		textureWrap = gl.REPEAT
+	}
+	var minFilter int32 = gl.NEAREST
+	if mipmapLevels > 1 {
+		minFilter = gl.LINEAR_MIPMAP_LINEAR
+	}
+
+	rgbaImg := image.NewRGBA(img.Img.Bounds())
+	draw.Draw(rgbaImg, rgbaImg.Bounds(), img.Img, image.Pt(0, 0), draw.Src)
+
+	gl.GenTextures(1, &imageTexture.TextureHandle)
+	gl.BindTexture(gl.TEXTURE_2D, imageTexture.TextureHandle)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, textureWrap)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, textureWrap)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, minFilter)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	applyAnisotropy(gl.TEXTURE_2D, anisotropy)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA8, int32(img.Img.Bounds().Max.X), int32(img.Img.Bounds().Max.Y), 0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(rgbaImg.Pix))
+	if mipmapLevels > 1 {
+		gl.GenerateMipmap(gl.TEXTURE_2D)
+	}
+
+	imageTexture.TextureSize = mgl32.Vec2{float32(img.Img.Bounds().Max.X), float32(img.Img.Bounds().Max.Y)}
+
+	return imageTexture
+}
+
+// CreateHDRTexture decodes a Radiance (.hdr) or OpenEXR (.exr) image into a
+// GL_RGBA32F texture, for IBL/skybox/decal workflows that need more
+// precision and range than CreateImageTexture's 8-bit RGBA.
+func CreateHDRTexture(name string, mipmapLevels int32, anisotropy float32) (ImageTexture, error) {
+	return createHDRTexture(name, mipmapLevels, anisotropy, false)
+}
+
+// CreateHDRTextureHalfFloat is CreateHDRTexture, but stores the decoded image
+// as GL_RGBA16F (gl.HALF_FLOAT) instead of GL_RGBA32F. Half the memory and
+// bandwidth of the 32F path, at reduced range/precision; fine for most
+// IBL/skybox source textures, which rarely need full 32-bit float range.
+func CreateHDRTextureHalfFloat(name string, mipmapLevels int32, anisotropy float32) (ImageTexture, error) {
+	return createHDRTexture(name, mipmapLevels, anisotropy, true)
+}
+
+func createHDRTexture(name string, mipmapLevels int32, anisotropy float32, halfFloat bool) (ImageTexture, error) {
+
+	f, err := os.Open(name)
+	if err != nil {
+		return ImageTexture{}, err
+	}
+	defer f.Close()
+
+	img, _, err := hdr.Decode(f)
+	if err != nil {
+		return ImageTexture{}, fmt.Errorf("failed to decode HDR image %v: %v", name, err)
+	}
+
+	bounds := img.Bounds()
+	width, height := int32(bounds.Dx()), int32(bounds.Dy())
+
+	pixels := make([]float32, width*height*4)
+	i := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.HDRAt(x, y).HDRRGBA()
+			pixels[i+0] = float32(r)
+			pixels[i+1] = float32(g)
+			pixels[i+2] = float32(b)
+			pixels[i+3] = float32(a)
+			i += 4
+		}
+	}
+
+	var tex uint32
+	gl.GenTextures(1, &tex)
+	gl.BindTexture(gl.TEXTURE_2D, tex)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	var minFilter int32 = gl.LINEAR
+	if mipmapLevels > 1 {
+		minFilter = gl.LINEAR_MIPMAP_LINEAR
+	}
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, minFilter)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	applyAnisotropy(gl.TEXTURE_2D, anisotropy)
+
+	if halfFloat {
+		halfPixels := make([]uint16, len(pixels))
+		for i, p := range pixels {
+			halfPixels[i] = float32ToHalfFloat(p)
+		}
+		gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA16F, width, height, 0, gl.RGBA, gl.HALF_FLOAT, gl.Ptr(halfPixels))
+	} else {
+		gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA32F, width, height, 0, gl.RGBA, gl.FLOAT, gl.Ptr(pixels))
+	}
+	if mipmapLevels > 1 {
+		gl.GenerateMipmap(gl.TEXTURE_2D)
+	}
+
+	return ImageTexture{TextureHandle: tex, TextureSize: mgl32.Vec2{float32(width), float32(height)}}, nil
+}
+
+// float32ToHalfFloat converts a float32 to the bit pattern of an IEEE 754
+// binary16 value (GL_HALF_FLOAT), rounding to nearest and flushing values
+// that overflow the half-float exponent range to infinity.
+func float32ToHalfFloat(f float32) uint16 {
+	bits := math.Float32bits(f)
+
+	sign := uint16((bits >> 16) & 0x8000)
+	exp := int32((bits>>23)&0xff) - 127 + 15
+	mantissa := bits & 0x7fffff
+
+	if exp <= 0 {
+		return sign
+	}
+	if exp >= 0x1f {
+		return sign | 0x7c00
+	}
+
+	half := uint32(exp)<<10 | mantissa>>13
+	// A carry out of the mantissa bumps the exponent, up to infinity.
+	if mantissa&0x1000 != 0 {
+		half++
+	}
+	return sign | uint16(half)
+}
+
+// CreateCubeMap loads six equally sized images, in the order
+// +X, -X, +Y, -Y, +Z, -Z, and uploads them as the faces of a seamless cube
+// map texture.
+func CreateCubeMap(faces [6]string, mipmapLevels int32, anisotropy float32) (uint32, error) {
+
+	var tex uint32
+	gl.GenTextures(1, &tex)
+	gl.BindTexture(gl.TEXTURE_CUBE_MAP, tex)
+
+	var size int32
+	for i, name := range faces {
+		img, err := LoadImage(name)
+		if err != nil {
+			gl.DeleteTextures(1, &tex)
+			return 0, fmt.Errorf("failed to load cube map face %v: %v", name, err)
+		}
+
+		rgbaImg := image.NewRGBA(img.Img.Bounds())
+		draw.Draw(rgbaImg, rgbaImg.Bounds(), img.Img, image.Pt(0, 0), draw.Src)
+
+		width, height := int32(rgbaImg.Bounds().Max.X), int32(rgbaImg.Bounds().Max.Y)
+		if i == 0 {
+			if width != height {
+				gl.DeleteTextures(1, &tex)
+				return 0, fmt.Errorf("CreateCubeMap: face %v is %vx%v, cube map faces must be square", name, width, height)
+			}
+			size = width
+		} else if width != size || height != size {
+			gl.DeleteTextures(1, &tex)
+			return 0, fmt.Errorf("CreateCubeMap: face %v is %vx%v, expected %vx%v", name, width, height, size, size)
+		}
+
+		gl.TexImage2D(uint32(gl.TEXTURE_CUBE_MAP_POSITIVE_X+i), 0, gl.RGBA8,
+			width, height, 0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(rgbaImg.Pix))
+	}
+
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_WRAP_R, gl.CLAMP_TO_EDGE)
+	var minFilter int32 = gl.LINEAR
+	if mipmapLevels > 1 {
+		minFilter = gl.LINEAR_MIPMAP_LINEAR
+	}
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_MIN_FILTER, minFilter)
+	gl.TexParameteri(gl.TEXTURE_CUBE_MAP, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	applyAnisotropy(gl.TEXTURE_CUBE_MAP, anisotropy)
+	// Seamless filtering across cube faces avoids visible seams on blurry
+	// (e.g. roughness-filtered) reflections.
+	gl.Enable(gl.TEXTURE_CUBE_MAP_SEAMLESS)
+
+	if mipmapLevels > 1 {
+		gl.GenerateMipmap(gl.TEXTURE_CUBE_MAP)
+	}
+
+	gl.BindTexture(gl.TEXTURE_CUBE_MAP, 0)
+
+	return tex, nil
+}
+
+// CreateTextureArray loads a set of equally sized images into the layers of
+// a single GL_TEXTURE_2D_ARRAY, in the order given.
+func CreateTextureArray(images []string, mipmapLevels int32, anisotropy float32) (uint32, error) {
+
+	if len(images) == 0 {
+		return 0, fmt.Errorf("CreateTextureArray: no images given")
+	}
+
+	layers := make([]*image.RGBA, len(images))
+	var width, height int32
+
+	for i, name := range images {
+		img, err := LoadImage(name)
+		if err != nil {
+			return 0, fmt.Errorf("failed to load texture array layer %v: %v", name, err)
+		}
+
+		rgbaImg := image.NewRGBA(img.Img.Bounds())
+		draw.Draw(rgbaImg, rgbaImg.Bounds(), img.Img, image.Pt(0, 0), draw.Src)
+		layers[i] = rgbaImg
+
+		if i == 0 {
+			width = int32(rgbaImg.Bounds().Max.X)
+			height = int32(rgbaImg.Bounds().Max.Y)
+		} else if int32(rgbaImg.Bounds().Max.X) != width || int32(rgbaImg.Bounds().Max.Y) != height {
+			return 0, fmt.Errorf("CreateTextureArray: layer %v is %vx%v, expected %vx%v", name, rgbaImg.Bounds().Max.X, rgbaImg.Bounds().Max.Y, width, height)
+		}
+	}
+
+	levels := mipmapLevels
+	if levels < 1 {
+		levels = 1
+	}
+
+	var tex uint32
+	gl.GenTextures(1, &tex)
+	gl.BindTexture(gl.TEXTURE_2D_ARRAY, tex)
+	gl.TexParameteri(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	var minFilter int32 = gl.LINEAR
+	if levels > 1 {
+		minFilter = gl.LINEAR_MIPMAP_LINEAR
+	}
+	gl.TexParameteri(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_MIN_FILTER, minFilter)
+	gl.TexParameteri(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	applyAnisotropy(gl.TEXTURE_2D_ARRAY, anisotropy)
+
+	gl.TexStorage3D(gl.TEXTURE_2D_ARRAY, levels, gl.RGBA8, width, height, int32(len(layers)))
+
+	for i, rgbaImg := range layers {
+		gl.TexSubImage3D(gl.TEXTURE_2D_ARRAY, 0, 0, 0, int32(i), width, height, 1, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(rgbaImg.Pix))
+	}
+
+	if levels > 1 {
+		gl.GenerateMipmap(gl.TEXTURE_2D_ARRAY)
+	}
+
+	gl.BindTexture(gl.TEXTURE_2D_ARRAY, 0)
+
+	return tex, nil
+}
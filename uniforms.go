@@ -0,0 +1,102 @@
+package mtOpenGL
+
+import (
+	"fmt"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// UniformCache lazily resolves glGetUniformLocation by name and caches the
+// result, so repeated SetX calls for the same uniform don't each pay for a
+// driver round-trip. Build one per program with NewUniformCache.
+type UniformCache struct {
+	program   uint32
+	locations map[string]int32
+}
+
+// NewUniformCache builds a UniformCache for program, the uint32 handle
+// returned by NewProgram (Program.Handle) or NewComputeProgram.
+func NewUniformCache(program uint32) *UniformCache {
+	return &UniformCache{program: program, locations: map[string]int32{}}
+}
+
+func (c *UniformCache) location(name string) int32 {
+	if loc, ok := c.locations[name]; ok {
+		return loc
+	}
+	loc := gl.GetUniformLocation(c.program, gl.Str(name+"\x00"))
+	c.locations[name] = loc
+	return loc
+}
+
+// SetInt sets an integer (or sampler) uniform.
+func (c *UniformCache) SetInt(name string, v int32) {
+	gl.ProgramUniform1i(c.program, c.location(name), v)
+}
+
+// SetFloat sets a float uniform.
+func (c *UniformCache) SetFloat(name string, v float32) {
+	gl.ProgramUniform1f(c.program, c.location(name), v)
+}
+
+// SetVec2 sets a vec2 uniform.
+func (c *UniformCache) SetVec2(name string, v mgl32.Vec2) {
+	gl.ProgramUniform2fv(c.program, c.location(name), 1, &v[0])
+}
+
+// SetVec3 sets a vec3 uniform.
+func (c *UniformCache) SetVec3(name string, v mgl32.Vec3) {
+	gl.ProgramUniform3fv(c.program, c.location(name), 1, &v[0])
+}
+
+// SetVec4 sets a vec4 uniform.
+func (c *UniformCache) SetVec4(name string, v mgl32.Vec4) {
+	gl.ProgramUniform4fv(c.program, c.location(name), 1, &v[0])
+}
+
+// SetMat3 sets a mat3 uniform.
+func (c *UniformCache) SetMat3(name string, v mgl32.Mat3) {
+	gl.ProgramUniformMatrix3fv(c.program, c.location(name), 1, false, &v[0])
+}
+
+// SetMat4 sets a mat4 uniform.
+func (c *UniformCache) SetMat4(name string, v mgl32.Mat4) {
+	gl.ProgramUniformMatrix4fv(c.program, c.location(name), 1, false, &v[0])
+}
+
+// SetSampler sets the sampler uniform name to the texture unit index unit.
+// It does not itself call gl.ActiveTexture/gl.BindTexture; it only
+// tells the shader which unit to sample from.
+func (c *UniformCache) SetSampler(name string, unit int32) {
+	gl.ProgramUniform1i(c.program, c.location(name), unit)
+}
+
+// SetUniforms is a bulk setter: it dispatches on the dynamic type of each
+// value the same way the typed SetX methods do, so callers can push a batch
+// of uniforms without writing out a SetX call for every one of them.
+func (c *UniformCache) SetUniforms(values map[string]interface{}) error {
+	for name, v := range values {
+		switch val := v.(type) {
+		case int32:
+			c.SetInt(name, val)
+		case int:
+			c.SetInt(name, int32(val))
+		case float32:
+			c.SetFloat(name, val)
+		case mgl32.Vec2:
+			c.SetVec2(name, val)
+		case mgl32.Vec3:
+			c.SetVec3(name, val)
+		case mgl32.Vec4:
+			c.SetVec4(name, val)
+		case mgl32.Mat3:
+			c.SetMat3(name, val)
+		case mgl32.Mat4:
+			c.SetMat4(name, val)
+		default:
+			return fmt.Errorf("SetUniforms: unsupported uniform type %T for %q", v, name)
+		}
+	}
+	return nil
+}
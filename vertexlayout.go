@@ -0,0 +1,175 @@
+package mtOpenGL
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.5-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// VertexAttribute describes a single attribute within an interleaved vertex,
+// as passed to gl.VertexAttribPointer. Offset is filled in automatically by
+// NewVertexLayout and does not need to be set by the caller.
+type VertexAttribute struct {
+	Index      uint32
+	Size       int32
+	Type       uint32 // gl.FLOAT, gl.UNSIGNED_BYTE, gl.UNSIGNED_SHORT, gl.UNSIGNED_INT, ...
+	Normalized bool
+	Offset     int
+}
+
+// VertexLayout is the fully resolved set of attributes for one interleaved
+// vertex, together with the stride computed from them.
+type VertexLayout struct {
+	Attributes []VertexAttribute
+	Stride     int32
+}
+
+// NewVertexLayout packs the given attributes back to back in the order
+// given, computing each Offset and the overall Stride automatically.
+func NewVertexLayout(attributes ...VertexAttribute) VertexLayout {
+	var offset int32
+	for i := range attributes {
+		attributes[i].Offset = int(offset)
+		offset += attributes[i].Size * attribTypeSize(attributes[i].Type)
+	}
+	return VertexLayout{Attributes: attributes, Stride: offset}
+}
+
+func attribTypeSize(t uint32) int32 {
+	switch t {
+	case gl.BYTE, gl.UNSIGNED_BYTE:
+		return 1
+	case gl.SHORT, gl.UNSIGNED_SHORT, gl.HALF_FLOAT:
+		return 2
+	case gl.INT, gl.UNSIGNED_INT, gl.FLOAT:
+		return 4
+	default:
+		return 4
+	}
+}
+
+// resolveData returns a GL-ready pointer and byte size for the common vertex
+// data shapes we need to upload, so callers don't have to compute the size
+// of every slice element by hand.
+func resolveData(data interface{}) (unsafe.Pointer, int, error) {
+	switch v := data.(type) {
+	case []byte:
+		return gl.Ptr(v), len(v), nil
+	case []float32:
+		return gl.Ptr(v), len(v) * 4, nil
+	case []uint16:
+		return gl.Ptr(v), len(v) * 2, nil
+	case []uint32:
+		return gl.Ptr(v), len(v) * 4, nil
+	case []mgl32.Vec2:
+		return gl.Ptr(v), len(v) * int(unsafe.Sizeof(mgl32.Vec2{})), nil
+	case []mgl32.Vec3:
+		return gl.Ptr(v), len(v) * int(unsafe.Sizeof(mgl32.Vec3{})), nil
+	case []mgl32.Vec4:
+		return gl.Ptr(v), len(v) * int(unsafe.Sizeof(mgl32.Vec4{})), nil
+	case []mgl32.Mat4:
+		return gl.Ptr(v), len(v) * int(unsafe.Sizeof(mgl32.Mat4{})), nil
+	default:
+		return nil, 0, fmt.Errorf("resolveData: unsupported vertex data type %T", data)
+	}
+}
+
+// resolveIndices is resolveData's counterpart for index buffers, additionally
+// reporting the GL index type so callers know how to issue glDrawElements.
+func resolveIndices(indices interface{}) (unsafe.Pointer, int, uint32, error) {
+	switch v := indices.(type) {
+	case []uint16:
+		return gl.Ptr(v), len(v) * 2, gl.UNSIGNED_SHORT, nil
+	case []uint32:
+		return gl.Ptr(v), len(v) * 4, gl.UNSIGNED_INT, nil
+	default:
+		return nil, 0, 0, fmt.Errorf("resolveIndices: unsupported index type %T", indices)
+	}
+}
+
+// resolveDataPtr returns a GL-ready pointer into the backing array of dst,
+// which must be a pointer to one of resolveData's supported slice types, so
+// glGetBufferSubData-style calls can write directly into caller-owned
+// memory.
+func resolveDataPtr(dst interface{}) (unsafe.Pointer, error) {
+	switch v := dst.(type) {
+	case *[]byte:
+		return gl.Ptr(*v), nil
+	case *[]float32:
+		return gl.Ptr(*v), nil
+	case *[]uint16:
+		return gl.Ptr(*v), nil
+	case *[]uint32:
+		return gl.Ptr(*v), nil
+	case *[]mgl32.Vec2:
+		return gl.Ptr(*v), nil
+	case *[]mgl32.Vec3:
+		return gl.Ptr(*v), nil
+	case *[]mgl32.Vec4:
+		return gl.Ptr(*v), nil
+	case *[]mgl32.Mat4:
+		return gl.Ptr(*v), nil
+	default:
+		return nil, fmt.Errorf("resolveDataPtr: unsupported destination type %T", dst)
+	}
+}
+
+func indexTypeSize(indexType uint32) int32 {
+	if indexType == gl.UNSIGNED_SHORT {
+		return 2
+	}
+	return 4
+}
+
+// GenerateBufferFromLayout is the generic replacement for the hard-coded
+// GenerateBufferFrom* helpers: it uploads an arbitrary interleaved vertex
+// blob described by layout, plus an optional uint16/uint32 index buffer, and
+// wires up one EnableVertexAttribArray/VertexAttribPointer call per
+// attribute. usage is one of gl.STATIC_DRAW/gl.DYNAMIC_DRAW/gl.STREAM_DRAW.
+func GenerateBufferFromLayout(bufferObject *MeshBuffer, data interface{}, layout VertexLayout, indices interface{}, usage uint32) error {
+
+	ptr, size, err := resolveData(data)
+	if err != nil {
+		return err
+	}
+	if layout.Stride <= 0 {
+		return fmt.Errorf("GenerateBufferFromLayout: layout has no attributes")
+	}
+
+	gl.GenBuffers(1, &bufferObject.ArrayBuffer)
+	gl.BindBuffer(gl.ARRAY_BUFFER, bufferObject.ArrayBuffer)
+	gl.BufferData(gl.ARRAY_BUFFER, size, ptr, usage)
+
+	gl.GenVertexArrays(1, &bufferObject.VertexBuffer)
+	gl.BindVertexArray(bufferObject.VertexBuffer)
+
+	for _, attr := range layout.Attributes {
+		gl.EnableVertexAttribArray(attr.Index)
+		gl.VertexAttribPointer(attr.Index, attr.Size, attr.Type, attr.Normalized, layout.Stride, gl.PtrOffset(attr.Offset))
+	}
+
+	bufferObject.VertexCount = int32(size) / layout.Stride
+
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+
+	if indices != nil {
+		idxPtr, idxSize, idxType, err := resolveIndices(indices)
+		if err != nil {
+			return err
+		}
+
+		gl.GenBuffers(1, &bufferObject.IndexBuffer)
+		gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, bufferObject.IndexBuffer)
+		gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, idxSize, idxPtr, usage)
+		gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, 0)
+
+		bufferObject.IndexCount = int32(idxSize) / indexTypeSize(idxType)
+		bufferObject.IndexType = idxType
+	}
+
+	gl.BindVertexArray(0)
+
+	return nil
+}